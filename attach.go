@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"golang.org/x/term"
+)
+
+// attachToDevContainer attaches the current process's stdio to containerID
+// using the Docker SDK directly, instead of shelling out to `docker attach`.
+// This drops the dependency on the docker CLI being on PATH and lets
+// docker-dev observe attach errors in-band rather than through a goroutine
+// that used to call log.Fatalf. tty must match the container's
+// config.Tty: attached output is only frame-multiplexed (and needs
+// stdcopy.StdCopy) when the container was created without a TTY.
+func attachToDevContainer(ctx context.Context, cli *client.Client, sigCh chan os.Signal, containerID string, tty bool) {
+	hijacked, err := cli.ContainerAttach(ctx, containerID, container.AttachOptions{
+		Stream: true,
+		Stdin:  true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		fatalUnlessRecoverable(fmt.Sprintf("attaching to container %s", containerID), err)
+	}
+
+	fd := int(os.Stdin.Fd())
+	prevState, rawErr := term.MakeRaw(fd)
+	if rawErr != nil {
+		log.Printf("Warning: could not put terminal in raw mode: %v", rawErr)
+	}
+
+	resizeContainerTTY(ctx, cli, containerID)
+	stopResizeWatch := watchTerminalResize(ctx, cli, containerID)
+
+	go func() {
+		io.Copy(hijacked.Conn, os.Stdin)
+		hijacked.CloseWrite()
+	}()
+
+	go func() {
+		var copyErr error
+		if tty {
+			_, copyErr = io.Copy(os.Stdout, hijacked.Reader)
+		} else {
+			_, copyErr = stdcopy.StdCopy(os.Stdout, os.Stderr, hijacked.Reader)
+		}
+
+		stopResizeWatch()
+		if prevState != nil {
+			term.Restore(fd, prevState)
+		}
+		hijacked.Close()
+
+		if copyErr != nil && copyErr != io.EOF {
+			log.Printf("Error reading from attached container: %v", copyErr)
+		}
+
+		sigCh <- os.Interrupt
+	}()
+}
+
+// resizeContainerTTY tells the daemon about the current terminal size, so
+// full-screen programs inside the container (vim, htop, ...) reflow correctly.
+func resizeContainerTTY(ctx context.Context, cli *client.Client, containerID string) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return
+	}
+
+	if err := cli.ContainerResize(ctx, containerID, container.ResizeOptions{
+		Height: uint(height),
+		Width:  uint(width),
+	}); err != nil {
+		log.Printf("Warning: could not resize container TTY: %v", err)
+	}
+}
+
+// watchTerminalResize re-sends the terminal size to the daemon whenever this
+// process's controlling terminal is resized (SIGWINCH), returning a function
+// that stops the watch.
+func watchTerminalResize(ctx context.Context, cli *client.Client, containerID string) func() {
+	sigWinch := make(chan os.Signal, 1)
+	signal.Notify(sigWinch, syscall.SIGWINCH)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-sigWinch:
+				resizeContainerTTY(ctx, cli, containerID)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigWinch)
+		close(done)
+	}
+}
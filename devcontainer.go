@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+)
+
+// devContainerConfig mirrors the subset of the devcontainer.json spec that
+// docker-dev knows how to translate into a container.Config/HostConfig pair.
+// See https://containers.dev/implementors/json_reference/ for the full spec.
+type devContainerConfig struct {
+	Image             string            `json:"image"`
+	WorkspaceFolder   string            `json:"workspaceFolder"`
+	RemoteUser        string            `json:"remoteUser"`
+	ContainerEnv      map[string]string `json:"containerEnv"`
+	Mounts            []string          `json:"mounts"`
+	RunArgs           []string          `json:"runArgs"`
+	OnCreateCommand   json.RawMessage   `json:"onCreateCommand"`
+	PostCreateCommand json.RawMessage   `json:"postCreateCommand"`
+	PostStartCommand  json.RawMessage   `json:"postStartCommand"`
+	ForwardPorts      []int             `json:"forwardPorts"`
+}
+
+// languageProfile describes the default image and cache mount docker-dev
+// falls back to when the source tree has no devcontainer.json.
+type languageProfile struct {
+	name        string
+	marker      string
+	image       string
+	cacheMounts []mount.Mount
+	// binPaths are appended to the container's PATH; empty for profiles
+	// whose image already has its toolchain on PATH.
+	binPaths []string
+}
+
+var languageProfiles = []languageProfile{
+	{
+		name:   "go",
+		marker: "go.mod",
+		image:  "docker-dev-golang:latest",
+		cacheMounts: []mount.Mount{
+			{Type: mount.TypeBind, Source: filepath.Join(os.Getenv("HOME"), "go", "pkg"), Target: "/go/pkg"},
+		},
+		binPaths: []string{"/go/bin", "/usr/local/go/bin"},
+	},
+	{
+		name:   "node",
+		marker: "package.json",
+		image:  "docker-dev-node:latest",
+		cacheMounts: []mount.Mount{
+			{Type: mount.TypeBind, Source: filepath.Join(os.Getenv("HOME"), ".npm"), Target: "/root/.npm"},
+		},
+	},
+	{
+		name:   "python",
+		marker: "pyproject.toml",
+		image:  "docker-dev-python:latest",
+	},
+	{
+		name:   "python",
+		marker: "requirements.txt",
+		image:  "docker-dev-python:latest",
+	},
+	{
+		name:   "rust",
+		marker: "Cargo.toml",
+		image:  "docker-dev-rust:latest",
+		cacheMounts: []mount.Mount{
+			{Type: mount.TypeBind, Source: filepath.Join(os.Getenv("HOME"), ".cargo"), Target: "/usr/local/cargo"},
+		},
+	},
+	{
+		name:   "ruby",
+		marker: "Gemfile",
+		image:  "docker-dev-ruby:latest",
+	},
+	{
+		name:   "java",
+		marker: "pom.xml",
+		image:  "docker-dev-java:latest",
+		cacheMounts: []mount.Mount{
+			{Type: mount.TypeBind, Source: filepath.Join(os.Getenv("HOME"), ".m2"), Target: "/root/.m2"},
+		},
+	},
+}
+
+// defaultLanguageProfile is used when sourcePath matches none of the known
+// markers, preserving docker-dev's original Go-only behaviour.
+var defaultLanguageProfile = languageProfiles[0]
+
+// detectLanguageProfile inspects sourcePath for well-known project files and
+// returns the matching default image and cache mounts.
+func detectLanguageProfile(sourcePath string) languageProfile {
+	for _, profile := range languageProfiles {
+		if _, err := os.Stat(filepath.Join(sourcePath, profile.marker)); err == nil {
+			return profile
+		}
+	}
+
+	return defaultLanguageProfile
+}
+
+// findDevContainerFile looks for a devcontainer.json under sourcePath, first
+// in the conventional .devcontainer/ subdirectory, then at the project root.
+func findDevContainerFile(sourcePath string) string {
+	candidates := []string{
+		filepath.Join(sourcePath, ".devcontainer", "devcontainer.json"),
+		filepath.Join(sourcePath, "devcontainer.json"),
+	}
+
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+// loadDevContainerConfig parses the devcontainer.json under sourcePath, if
+// any is present. It returns a nil config and a nil error when none is found.
+func loadDevContainerConfig(sourcePath string) (*devContainerConfig, error) {
+	path := findDevContainerFile(sourcePath)
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	var cfg devContainerConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// parseDevContainerMount turns a devcontainer.json mount entry, either the
+// shorthand "source=...,target=...,type=bind" string form or a bind-mount
+// shorthand like "src:dst", into a mount.Mount.
+func parseDevContainerMount(raw string) (mount.Mount, error) {
+	m := mount.Mount{Type: mount.TypeBind}
+
+	if !strings.Contains(raw, "=") {
+		parts := strings.SplitN(raw, ":", 2)
+		if len(parts) != 2 {
+			return m, fmt.Errorf("invalid mount %q", raw)
+		}
+		m.Source, m.Target = parts[0], parts[1]
+		return m, nil
+	}
+
+	for _, field := range strings.Split(raw, ",") {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return m, fmt.Errorf("invalid mount field %q in %q", field, raw)
+		}
+
+		switch strings.TrimSpace(kv[0]) {
+		case "source", "src":
+			m.Source = kv[1]
+		case "target", "dst", "destination":
+			m.Target = kv[1]
+		case "type":
+			m.Type = mount.Type(kv[1])
+		case "readonly":
+			m.ReadOnly = kv[1] == "" || kv[1] == "true"
+		}
+	}
+
+	if m.Source == "" || m.Target == "" {
+		return m, fmt.Errorf("mount %q is missing source or target", raw)
+	}
+
+	return m, nil
+}
+
+// devContainerConfigurationFromSpec translates a parsed devcontainer.json
+// into the container.Config/HostConfig/NetworkingConfig triple docker-dev
+// needs to start the dev container.
+func devContainerConfigurationFromSpec(containerJSON *types.ContainerJSON, devCfg *devContainerConfig, sourcePath, targetPath string) (*container.Config, *container.HostConfig, *network.NetworkingConfig) {
+	if devCfg.WorkspaceFolder != "" {
+		targetPath = devCfg.WorkspaceFolder
+	}
+
+	config := *containerJSON.Config
+	config.Image = devCfg.Image
+	config.WorkingDir = targetPath
+	config.Cmd = []string{}
+	config.Entrypoint = []string{"/bin/sh"}
+	if devCfg.RemoteUser != "" {
+		config.User = devCfg.RemoteUser
+	}
+
+	for key, value := range devCfg.ContainerEnv {
+		config.Env = append(config.Env, key+"="+value)
+	}
+	config.Env = append(config.Env, "DEV_CONTAINER=true")
+
+	config.AttachStdin = true
+	config.AttachStdout = true
+	config.AttachStderr = true
+	config.Tty = true
+	config.OpenStdin = true
+	config.StdinOnce = true
+
+	if len(devCfg.ForwardPorts) > 0 {
+		config.ExposedPorts = nat.PortSet{}
+		for _, port := range devCfg.ForwardPorts {
+			config.ExposedPorts[nat.Port(strconv.Itoa(port)+"/tcp")] = struct{}{}
+		}
+	}
+
+	hostConfig := *containerJSON.HostConfig
+	hostConfig.Mounts = []mount.Mount{
+		{Type: mount.TypeBind, Source: sourcePath, Target: targetPath},
+		{Type: mount.TypeBind, Source: os.Getenv("HOME") + "/.ssh", Target: "/root/.ssh"},
+		{Type: mount.TypeBind, Source: os.Getenv("HOME") + "/.gitconfig", Target: "/root/.gitconfig"},
+	}
+
+	for _, raw := range devCfg.Mounts {
+		m, err := parseDevContainerMount(raw)
+		if err != nil {
+			log.Printf("Warning: skipping devcontainer.json mount: %v", err)
+			continue
+		}
+		hostConfig.Mounts = append(hostConfig.Mounts, m)
+	}
+
+	if len(devCfg.ForwardPorts) > 0 {
+		hostConfig.PortBindings = nat.PortMap{}
+		for _, port := range devCfg.ForwardPorts {
+			p := nat.Port(strconv.Itoa(port) + "/tcp")
+			hostConfig.PortBindings[p] = []nat.PortBinding{{HostPort: strconv.Itoa(port)}}
+		}
+	}
+
+	// runArgs covers the long tail of `docker run` flags (--cap-add,
+	// --privileged, ...); translating the full surface isn't worth it, so we
+	// only handle the common case of extra env vars here.
+	for i := 0; i < len(devCfg.RunArgs); i++ {
+		if devCfg.RunArgs[i] == "-e" && i+1 < len(devCfg.RunArgs) {
+			config.Env = append(config.Env, devCfg.RunArgs[i+1])
+			i++
+		}
+	}
+
+	networkingConfig := network.NetworkingConfig{
+		EndpointsConfig: containerJSON.NetworkSettings.Networks,
+	}
+
+	return &config, &hostConfig, &networkingConfig
+}
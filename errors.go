@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+)
+
+// createAttempts bounds the create-fix-retry loop in
+// createContainerWithRecovery: one shot plus a few recovery attempts.
+const createAttempts = 4
+
+// retryBaseDelay is the starting backoff delay for transient Docker daemon
+// errors (connection refused, 5xx, ...); it doubles on each attempt.
+const retryBaseDelay = 500 * time.Millisecond
+
+// isTransientDockerError reports whether err is worth retrying as-is: the
+// daemon is momentarily unreachable or returned a 5xx, as opposed to a
+// well-formed rejection like NotFound or Conflict that calling code should
+// act on instead of blindly retrying.
+func isTransientDockerError(err error) bool {
+	return client.IsErrConnectionFailed(err) || errdefs.IsSystem(err) || errdefs.IsUnknown(err)
+}
+
+// pullImage pulls imageRef, streaming the daemon's progress output so a slow
+// pull doesn't look like a hang.
+func pullImage(ctx context.Context, cli *client.Client, imageRef string) error {
+	log.Printf("Image %s not found locally, pulling...", imageRef)
+
+	out, err := cli.ImagePull(ctx, imageRef, types.ImagePullOptions{})
+	if err != nil {
+		return fmt.Errorf("pulling image %s: %w", imageRef, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(io.Discard, out); err != nil {
+		return fmt.Errorf("streaming pull progress for %s: %w", imageRef, err)
+	}
+
+	return nil
+}
+
+// removeStaleContainerByName force-removes a stopped or orphaned container
+// that is holding onto name, so a ContainerCreate conflict can be retried.
+// name may carry the leading slash that ContainerInspect reports.
+func removeStaleContainerByName(ctx context.Context, cli *client.Client, name string) error {
+	id, err := getContainerIDByName(cli, ctx, strings.TrimPrefix(name, "/"))
+	if err != nil {
+		// Nothing with that name, nothing to clean up.
+		return nil
+	}
+
+	log.Printf("Warning: removing stale container %s to free up its name", name)
+
+	return cli.ContainerRemove(ctx, id, container.RemoveOptions{Force: true})
+}
+
+// createContainerWithRecovery wraps cli.ContainerCreate with the recovery
+// docker-dev knows how to perform automatically: if the name is already in
+// use, the stale container is removed and creation retried; if the image is
+// missing, it is pulled and creation retried; transient daemon errors are
+// retried with exponential backoff.
+func createContainerWithRecovery(ctx context.Context, cli *client.Client, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, name string) (container.CreateResponse, error) {
+	var lastErr error
+
+	for attempt := 0; attempt < createAttempts; attempt++ {
+		resp, err := cli.ContainerCreate(ctx, config, hostConfig, networkingConfig, nil, name)
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+
+		switch {
+		case errdefs.IsConflict(err):
+			if cleanupErr := removeStaleContainerByName(ctx, cli, name); cleanupErr != nil {
+				return container.CreateResponse{}, fmt.Errorf("removing stale container %s: %w", name, cleanupErr)
+			}
+		case errdefs.IsNotFound(err):
+			if pullErr := pullImage(ctx, cli, config.Image); pullErr != nil {
+				return container.CreateResponse{}, pullErr
+			}
+		case isTransientDockerError(err):
+			delay := retryBaseDelay * time.Duration(1<<attempt)
+			log.Printf("Warning: transient Docker error creating container, retrying in %s: %v", delay, err)
+			time.Sleep(delay)
+		default:
+			return container.CreateResponse{}, err
+		}
+	}
+
+	return container.CreateResponse{}, fmt.Errorf("giving up creating container %s after %d attempts: %w", name, createAttempts, lastErr)
+}
+
+// startContainerWithRecovery wraps cli.ContainerStart, pulling the image and
+// retrying whenever the daemon reports it missing, and retrying transient
+// daemon errors with exponential backoff, for at most createAttempts tries.
+func startContainerWithRecovery(ctx context.Context, cli *client.Client, containerID, imageRef string) error {
+	var lastErr error
+
+	for attempt := 0; attempt < createAttempts; attempt++ {
+		err := cli.ContainerStart(ctx, containerID, container.StartOptions{})
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		switch {
+		case errdefs.IsNotFound(err):
+			if pullErr := pullImage(ctx, cli, imageRef); pullErr != nil {
+				return pullErr
+			}
+		case isTransientDockerError(err):
+			delay := retryBaseDelay * time.Duration(1<<attempt)
+			log.Printf("Warning: transient Docker error starting container, retrying in %s: %v", delay, err)
+			time.Sleep(delay)
+		default:
+			return err
+		}
+	}
+
+	return fmt.Errorf("giving up starting container %s after %d attempts: %w", containerID, createAttempts, lastErr)
+}
+
+// stopContainerWithRecovery wraps cli.ContainerStop, retrying transient
+// daemon errors with exponential backoff. A NotFound error (the container is
+// already gone) is returned as-is so callers can treat it as a no-op.
+func stopContainerWithRecovery(ctx context.Context, cli *client.Client, containerID string, options container.StopOptions) error {
+	var lastErr error
+
+	for attempt := 0; attempt < createAttempts; attempt++ {
+		err := cli.ContainerStop(ctx, containerID, options)
+		if err == nil || errdefs.IsNotFound(err) {
+			return err
+		}
+		lastErr = err
+
+		if !isTransientDockerError(err) {
+			return err
+		}
+
+		delay := retryBaseDelay * time.Duration(1<<attempt)
+		log.Printf("Warning: transient Docker error stopping container, retrying in %s: %v", delay, err)
+		time.Sleep(delay)
+	}
+
+	return fmt.Errorf("giving up stopping container %s after %d attempts: %w", containerID, createAttempts, lastErr)
+}
+
+// removeContainerWithRecovery wraps cli.ContainerRemove, retrying transient
+// daemon errors with exponential backoff. A NotFound error is returned as-is
+// so callers can treat it as a no-op.
+func removeContainerWithRecovery(ctx context.Context, cli *client.Client, containerID string, options container.RemoveOptions) error {
+	var lastErr error
+
+	for attempt := 0; attempt < createAttempts; attempt++ {
+		err := cli.ContainerRemove(ctx, containerID, options)
+		if err == nil || errdefs.IsNotFound(err) {
+			return err
+		}
+		lastErr = err
+
+		if !isTransientDockerError(err) {
+			return err
+		}
+
+		delay := retryBaseDelay * time.Duration(1<<attempt)
+		log.Printf("Warning: transient Docker error removing container, retrying in %s: %v", delay, err)
+		time.Sleep(delay)
+	}
+
+	return fmt.Errorf("giving up removing container %s after %d attempts: %w", containerID, createAttempts, lastErr)
+}
+
+// fatalUnlessRecoverable logs a clear, actionable message and exits for
+// errors docker-dev has no automatic recovery for (daemon unreachable,
+// unauthorized, ...), instead of a bare Fatalf stack of "Error X: %v".
+func fatalUnlessRecoverable(action string, err error) {
+	switch {
+	case client.IsErrConnectionFailed(err):
+		log.Fatalf("Could not reach the Docker daemon while %s: %v\nIs Docker running and is DOCKER_HOST set correctly?", action, err)
+	case errdefs.IsUnauthorized(err):
+		log.Fatalf("Not authorized while %s: %v\nCheck your Docker registry credentials.", action, err)
+	case errdefs.IsNotFound(err):
+		log.Fatalf("Not found while %s: %v", action, err)
+	default:
+		log.Fatalf("Error %s: %v", action, err)
+	}
+}
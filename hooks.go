@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"gopkg.in/yaml.v3"
+)
+
+// hookStage names the three points in the container lifecycle docker-dev
+// can run commands at, mirroring devcontainer.json's onCreateCommand,
+// postCreateCommand and postStartCommand.
+type hookStage string
+
+const (
+	stageOnCreate   hookStage = "onCreate"
+	stagePostCreate hookStage = "postCreate"
+	stagePostStart  hookStage = "postStart"
+)
+
+// hooksMarkerDir is where completion markers are recorded inside the dev
+// container, so a later --resume can skip onCreate/postCreate steps that
+// already ran. postStart always re-runs, since it typically just starts a
+// process (a language server, a watcher, ...).
+const hooksMarkerDir = "/var/lib/docker-dev/hooks"
+
+// dockerDevHooksFile is the repo-local fallback for lifecycle commands, for
+// projects that don't otherwise carry a devcontainer.json.
+const dockerDevHooksFile = ".docker-dev.yml"
+
+// hookSet is the ordered list of shell commands to run at each stage.
+type hookSet struct {
+	OnCreate   []string `yaml:"onCreate"`
+	PostCreate []string `yaml:"postCreate"`
+	PostStart  []string `yaml:"postStart"`
+}
+
+// commandsForStage returns the commands configured for stage.
+func (h *hookSet) commandsForStage(stage hookStage) []string {
+	switch stage {
+	case stageOnCreate:
+		return h.OnCreate
+	case stagePostCreate:
+		return h.PostCreate
+	case stagePostStart:
+		return h.PostStart
+	default:
+		return nil
+	}
+}
+
+// loadHooks builds the hook set for sourcePath: a devcontainer.json's
+// on/post-create/post-start commands take precedence over the matching
+// stage in .docker-dev.yml.
+func loadHooks(sourcePath string) (*hookSet, error) {
+	hooks := &hookSet{}
+
+	if devCfg, err := loadDevContainerConfig(sourcePath); err == nil && devCfg != nil {
+		hooks.OnCreate, _ = decodeHookCommand(devCfg.OnCreateCommand)
+		hooks.PostCreate, _ = decodeHookCommand(devCfg.PostCreateCommand)
+		hooks.PostStart, _ = decodeHookCommand(devCfg.PostStartCommand)
+	}
+
+	data, err := os.ReadFile(filepath.Join(sourcePath, dockerDevHooksFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return hooks, nil
+		}
+		return nil, fmt.Errorf("reading %s: %w", dockerDevHooksFile, err)
+	}
+
+	var fileHooks hookSet
+	if err := yaml.Unmarshal(data, &fileHooks); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", dockerDevHooksFile, err)
+	}
+
+	if len(hooks.OnCreate) == 0 {
+		hooks.OnCreate = fileHooks.OnCreate
+	}
+	if len(hooks.PostCreate) == 0 {
+		hooks.PostCreate = fileHooks.PostCreate
+	}
+	if len(hooks.PostStart) == 0 {
+		hooks.PostStart = fileHooks.PostStart
+	}
+
+	return hooks, nil
+}
+
+// decodeHookCommand accepts both the devcontainer.json string and
+// array-of-strings forms for a lifecycle command field.
+func decodeHookCommand(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		if single == "" {
+			return nil, nil
+		}
+		return []string{single}, nil
+	}
+
+	var multi []string
+	if err := json.Unmarshal(raw, &multi); err != nil {
+		return nil, fmt.Errorf("invalid lifecycle command: %w", err)
+	}
+
+	return multi, nil
+}
+
+// markerPath returns stage's completion marker path inside the container.
+func markerPath(stage hookStage) string {
+	return filepath.Join(hooksMarkerDir, string(stage)+".done")
+}
+
+// execInContainer runs argv inside containerID, streaming its combined
+// output to the user's terminal, and returns an error if it exits non-zero.
+func execInContainer(ctx context.Context, cli *client.Client, containerID string, argv []string) error {
+	execID, err := cli.ContainerExecCreate(ctx, containerID, types.ExecConfig{
+		Cmd:          argv,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return fmt.Errorf("creating exec: %w", err)
+	}
+
+	attachResp, err := cli.ContainerExecAttach(ctx, execID.ID, types.ExecStartCheck{})
+	if err != nil {
+		return fmt.Errorf("attaching to exec: %w", err)
+	}
+	defer attachResp.Close()
+
+	if _, err := stdcopy.StdCopy(os.Stdout, os.Stderr, attachResp.Reader); err != nil {
+		return fmt.Errorf("streaming exec output: %w", err)
+	}
+
+	inspect, err := cli.ContainerExecInspect(ctx, execID.ID)
+	if err != nil {
+		return fmt.Errorf("inspecting exec: %w", err)
+	}
+
+	if inspect.ExitCode != 0 {
+		return fmt.Errorf("exited with status %d", inspect.ExitCode)
+	}
+
+	return nil
+}
+
+// hookStageCompleted checks for stage's completion marker inside the container.
+func hookStageCompleted(ctx context.Context, cli *client.Client, containerID string, stage hookStage) bool {
+	return execInContainer(ctx, cli, containerID, []string{"/bin/sh", "-c", "test -f " + markerPath(stage)}) == nil
+}
+
+// markHookStageCompleted records stage's completion marker inside the container.
+func markHookStageCompleted(ctx context.Context, cli *client.Client, containerID string, stage hookStage) {
+	cmd := fmt.Sprintf("mkdir -p %s && touch %s", hooksMarkerDir, markerPath(stage))
+	if err := execInContainer(ctx, cli, containerID, []string{"/bin/sh", "-c", cmd}); err != nil {
+		log.Printf("Warning: could not record completion marker for %s: %v", stage, err)
+	}
+}
+
+// runHookStage executes every command configured for stage inside
+// containerID. onCreate/postCreate are skipped if their completion marker
+// is already present (so --resume doesn't redo setup work); postStart
+// always runs.
+func runHookStage(ctx context.Context, cli *client.Client, containerID string, stage hookStage, hooks *hookSet) {
+	commands := hooks.commandsForStage(stage)
+	if len(commands) == 0 {
+		return
+	}
+
+	if stage != stagePostStart && hookStageCompleted(ctx, cli, containerID, stage) {
+		log.Printf("Skipping %s hooks, already completed", stage)
+		return
+	}
+
+	for _, cmdline := range commands {
+		fmt.Printf("Running %s hook: %s\n", stage, cmdline)
+		if err := execInContainer(ctx, cli, containerID, []string{"/bin/sh", "-c", cmdline}); err != nil {
+			log.Printf("Warning: %s hook %q failed: %v", stage, cmdline, err)
+			return
+		}
+	}
+
+	if stage != stagePostStart {
+		markHookStageCompleted(ctx, cli, containerID, stage)
+	}
+}
+
+// runLifecycleHooks runs onCreate, then postCreate, then postStart, for
+// sourcePath's devcontainer.json / .docker-dev.yml, before the caller
+// attaches the user's terminal to containerID.
+func runLifecycleHooks(ctx context.Context, cli *client.Client, containerID, sourcePath string) {
+	hooks, err := loadHooks(sourcePath)
+	if err != nil {
+		log.Printf("Warning: ignoring invalid lifecycle hooks: %v", err)
+		return
+	}
+
+	runHookStage(ctx, cli, containerID, stageOnCreate, hooks)
+	runHookStage(ctx, cli, containerID, stagePostCreate, hooks)
+	runHookStage(ctx, cli, containerID, stagePostStart, hooks)
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
 
 )
 
@@ -52,34 +53,43 @@ func waitForContainerStop(cli *client.Client, ctx context.Context, containerID s
 	}
 }
 
-func recreateOriginalContainer(ctx context.Context, cli *client.Client, containerJSON *types.ContainerJSON, networkingConfig *network.NetworkingConfig) {
-	// Start the original container
-	resp, err := cli.ContainerCreate(ctx, containerJSON.Config, containerJSON.HostConfig, networkingConfig, nil, containerJSON.Name)
-	if err  != nil {
-		log.Fatalf("Error creating container: %v", err)
+func recreateOriginalContainer(ctx context.Context, cli *client.Client, containerJSON *types.ContainerJSON, networkingConfig *network.NetworkingConfig) string {
+	// Start the original container, recovering automatically from a stale
+	// name (left over from a previous failed swap) or a missing image.
+	resp, err := createContainerWithRecovery(ctx, cli, containerJSON.Config, containerJSON.HostConfig, networkingConfig, containerJSON.Name)
+	if err != nil {
+		fatalUnlessRecoverable(fmt.Sprintf("recreating container %s", containerJSON.Name), err)
 	}
 
-	if err := cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
-		log.Fatalf("Error starting container: %v", err)
+	if err := startContainerWithRecovery(ctx, cli, resp.ID, containerJSON.Config.Image); err != nil {
+		fatalUnlessRecoverable(fmt.Sprintf("starting recreated container %s", containerJSON.Name), err)
 	}
 
 	fmt.Printf("Original container %s started successfully\n", containerJSON.Name)
+
+	return resp.ID
 }
 
 func cleanupDevContainer(ctx context.Context, cli *client.Client, resp *container.CreateResponse) {
 	c, err := cli.ContainerInspect(context.Background(), resp.ID)
-	if err!= nil {
-		panic(err)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			println("Dev container already gone, nothing to clean up")
+			return
+		}
+		fatalUnlessRecoverable("inspecting dev container", err)
 	}
 
 	if c.State.Running {
 		timeout := 0
-		cli.ContainerStop(context.Background(), resp.ID, container.StopOptions{Timeout: &timeout})
+		if err := stopContainerWithRecovery(context.Background(), cli, resp.ID, container.StopOptions{Timeout: &timeout}); err != nil && !errdefs.IsNotFound(err) {
+			fatalUnlessRecoverable("stopping dev container", err)
+		}
 		waitForContainerStop(cli, ctx, resp.ID)
 	}
 
-	if err := cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{}); err != nil {
-		log.Fatalf("Error removing container: %v", err)
+	if err := removeContainerWithRecovery(ctx, cli, resp.ID, container.RemoveOptions{}); err != nil && !errdefs.IsNotFound(err) {
+		fatalUnlessRecoverable("removing dev container", err)
 	}
 
 	println("Container removed successfully")
@@ -90,46 +100,36 @@ func removeOriginalContainer(ctx context.Context, cli *client.Client, containerI
 
 	// Stop the running container
 	timeout := 0
-	if err := cli.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &timeout}); err != nil {
-		log.Fatalf("Error stopping container: %v", err)
+	if err := stopContainerWithRecovery(ctx, cli, containerID, container.StopOptions{Timeout: &timeout}); err != nil && !errdefs.IsNotFound(err) {
+		fatalUnlessRecoverable("stopping original container", err)
 	}
 
 	waitForContainerStop(cli, ctx, containerID)
 
-	if err := cli.ContainerRemove(ctx, containerID, container.RemoveOptions{}); err != nil {
-		log.Fatalf("Error removing container: %v", err)
+	if err := removeContainerWithRecovery(ctx, cli, containerID, container.RemoveOptions{}); err != nil && !errdefs.IsNotFound(err) {
+		fatalUnlessRecoverable("removing original container", err)
 	}
 }
 
-func startDevContainer(ctx context.Context, cli *client.Client, sigCh chan os.Signal, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, name string) *container.CreateResponse {
-	// Create the new container
-	resp, err := cli.ContainerCreate(ctx, config, hostConfig, networkingConfig, nil, name)
+func startDevContainer(ctx context.Context, cli *client.Client, sigCh chan os.Signal, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, name, sourcePath string) *container.CreateResponse {
+	// Create the new container, recovering automatically from a stale name
+	// or a missing image.
+	resp, err := createContainerWithRecovery(ctx, cli, config, hostConfig, networkingConfig, name)
 	if err != nil {
-		log.Fatalf("Error creating container: %v", err)
+		fatalUnlessRecoverable(fmt.Sprintf("creating dev container %s", name), err)
 	}
 
-	// Attach to the container by executing docker attach
-	cmd := exec.Command("docker", "attach", resp.ID)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	// Start the new container on an interactive shell
+	if err := startContainerWithRecovery(ctx, cli, resp.ID, config.Image); err != nil {
+		fatalUnlessRecoverable(fmt.Sprintf("starting dev container %s", name), err)
+	}
 
-	go func () {
-		err = cmd.Run()
-		if err != nil {
-			log.Fatalf("Error attaching to container: %v", err)
-		}
+	runLifecycleHooks(ctx, cli, resp.ID, sourcePath)
 
-		sigCh <- os.Interrupt
-	}()
+	attachToDevContainer(ctx, cli, sigCh, resp.ID, config.Tty)
 
 	println("Quit the container to restore the original one")
 
-	// Start the new container on an interactive shell
-	if err := cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
-		log.Fatalf("Error starting container: %v", err)
-	}
-
 	return &resp
 }
 
@@ -161,7 +161,37 @@ func cloneRemoteRepo(remote, branch string) string {
 	return targetDir
 }
 
+// devContainerConfiguration builds the container.Config/HostConfig/
+// NetworkingConfig triple for the dev container. It honours a
+// .devcontainer/devcontainer.json (or project-root devcontainer.json) under
+// sourcePath when present; otherwise it auto-detects the project's language
+// and falls back to the matching default image and cache mount.
 func devContainerConfiguration(containerJSON *types.ContainerJSON, newImage, sourcePath, targetPath string) (*container.Config, *container.HostConfig, *network.NetworkingConfig) {
+	devCfg, err := loadDevContainerConfig(sourcePath)
+	if err != nil {
+		log.Printf("Warning: ignoring invalid devcontainer.json: %v", err)
+		devCfg = nil
+	}
+
+	if devCfg != nil {
+		if newImage != "" {
+			devCfg.Image = newImage
+		}
+		if devCfg.Image == "" {
+			// devcontainer.json has no image (likely a build/dockerFile
+			// devcontainer, which this parser doesn't support); fall back to
+			// the auto-detected language default rather than creating the
+			// container with an empty image field.
+			devCfg.Image = detectLanguageProfile(sourcePath).image
+		}
+		return devContainerConfigurationFromSpec(containerJSON, devCfg, sourcePath, targetPath)
+	}
+
+	profile := detectLanguageProfile(sourcePath)
+	if newImage == "" {
+		newImage = profile.image
+	}
+
 	// Create new container configuration
 	config := *containerJSON.Config
 	config.Image = newImage
@@ -169,16 +199,19 @@ func devContainerConfiguration(containerJSON *types.ContainerJSON, newImage, sou
 	config.Cmd = []string{}
 	config.Entrypoint = []string{ "/bin/sh" }
 
-	// get PATH from original container Env
-	path := ""
-	for _, env := range config.Env {
-		if env[:4] == "PATH" {
-			path = env
-			break
+	// get PATH from original container Env and append the detected
+	// language's toolchain directories, if any
+	if len(profile.binPaths) > 0 {
+		path := ""
+		for _, env := range config.Env {
+			if len(env) >= 4 && env[:4] == "PATH" {
+				path = env
+				break
+			}
 		}
+		path = path + ":" + strings.Join(profile.binPaths, ":")
+		config.Env = append(config.Env, path)
 	}
-	path = path + ":/go/bin:/usr/local/go/bin"
-	config.Env = append(config.Env, path)
 	config.Env = append(config.Env, "DEV_CONTAINER=true")
 
 	config.AttachStdin = true
@@ -207,13 +240,8 @@ func devContainerConfiguration(containerJSON *types.ContainerJSON, newImage, sou
 			Source: os.Getenv("HOME") + "/.gitconfig",
 			Target: "/root/.gitconfig",
 		},
-		// Mount the go folder for go modules
-		{
-			Type:   mount.TypeBind,
-			Source: os.Getenv("HOME") + "/go/pkg",
-			Target: "/go/pkg",
-		},
 	}
+	hostConfig.Mounts = append(hostConfig.Mounts, profile.cacheMounts...)
 
 	networkingConfig := network.NetworkingConfig{
 		EndpointsConfig: containerJSON.NetworkSettings.Networks,
@@ -232,19 +260,24 @@ func devContainerConfiguration(containerJSON *types.ContainerJSON, newImage, sou
 func main() {
 	// Parse command-line arguments
 	containerName := flag.String("name", "", "Name of the running container")
+	project := flag.String("project", "", "Compose project to target instead of -name; picks a service interactively unless -service is set")
+	stack := flag.String("stack", "", "Alias for -project")
+	service := flag.String("service", "", "Compose service to target within -project/-stack")
 	sourcePath := flag.String("source", "", "Source path for the new volume mount")
 	targetPath := flag.String("target", "/app", "Target path for the new volume mount in the container")
-	newImage := flag.String("image", "docker-dev-golang:latest", "New image for the container")
+	newImage := flag.String("image", "", "New image for the container (defaults to the devcontainer.json image, or the detected language's default image)")
 	remote := flag.String("remote", "", "Remote git repository to clone")
 	branch := flag.String("branch", "master", "Branch to checkout")
+	resume := flag.Bool("resume", false, "Re-attach to the still-running dev container from -name's saved session")
+	abort := flag.Bool("abort", false, "Restore the original container from -name's saved session, even if the dev container is gone")
 	flag.Parse()
-	
-	if remote != nil && *remote != "" {
-		*sourcePath = cloneRemoteRepo(*remote, *branch)
+
+	if *project == "" {
+		*project = *stack
 	}
 
-	if *containerName == "" {
-		log.Fatalf("Argument for -name is required")
+	if *containerName == "" && *project == "" {
+		log.Fatalf("Argument for -name or -project/-stack is required")
 	}
 
 	ctx := context.Background()
@@ -253,10 +286,50 @@ func main() {
 		log.Fatalf("Error creating Docker client: %v", err)
 	}
 
-	// Container ID or name of the running container
-	containerID, err := getContainerIDByName(cli, ctx, *containerName)
-	if err != nil {
-		log.Fatalf("Error getting Docker container's id by name: %v", err)
+	if *resume || *abort {
+		if *containerName == "" {
+			name, err := resolveStackSessionName(*project, *service)
+			if err != nil {
+				log.Fatalf("Error resolving compose project %s to a saved session: %v", *project, err)
+			}
+			*containerName = name
+		}
+
+		if *resume {
+			resumeSession(ctx, cli, *containerName)
+		} else {
+			abortSession(ctx, cli, *containerName)
+		}
+		return
+	}
+
+	if remote != nil && *remote != "" {
+		*sourcePath = cloneRemoteRepo(*remote, *branch)
+	}
+
+	var containerID string
+	var stackNetworks []string
+
+	if *project != "" {
+		containers, err := listStackContainers(ctx, cli, *project)
+		if err != nil {
+			log.Fatalf("Error listing compose project %s: %v", *project, err)
+		}
+
+		target, err := pickStackService(containers, *service)
+		if err != nil {
+			log.Fatalf("Error picking a service in project %s: %v", *project, err)
+		}
+
+		containerID = target.ID
+		stackNetworks = target.Networks
+		*containerName = target.Name
+	} else {
+		// Container ID or name of the running container
+		containerID, err = getContainerIDByName(cli, ctx, *containerName)
+		if err != nil {
+			log.Fatalf("Error getting Docker container's id by name: %v", err)
+		}
 	}
 
 	// Fetch the current configuration of the running container
@@ -270,12 +343,32 @@ func main() {
 	name := containerJSON.Name + "-dev"
 
 	config, hostConfig, networkingConfig := devContainerConfiguration(&containerJSON, *newImage, *sourcePath, *targetPath)
-	
+
+	state := &sessionState{
+		ContainerName:     *containerName,
+		OriginalContainer: containerJSON,
+		NetworkingConfig:  *networkingConfig,
+		StackNetworks:     stackNetworks,
+		SourcePath:        *sourcePath,
+		Project:           *project,
+		Service:           *service,
+	}
+	if err := saveSessionState(state); err != nil {
+		log.Printf("Warning: could not persist session state: %v", err)
+	}
+
 	// Handle terminal signals for proper cleanup
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt)
 
-	resp := startDevContainer(ctx, cli, sigCh, config, hostConfig, networkingConfig, name)
+	resp := startDevContainer(ctx, cli, sigCh, config, hostConfig, networkingConfig, name, *sourcePath)
+
+	joinStackNetworks(ctx, cli, resp.ID, stackNetworks)
+
+	state.DevContainerID = resp.ID
+	if err := saveSessionState(state); err != nil {
+		log.Printf("Warning: could not persist session state: %v", err)
+	}
 
 	go func () {
 		// In case the main container's process fails
@@ -289,6 +382,9 @@ func main() {
 
 	cleanupDevContainer(ctx, cli, resp)
 
-	recreateOriginalContainer(ctx, cli, &containerJSON, networkingConfig)
+	originalID := recreateOriginalContainer(ctx, cli, &containerJSON, networkingConfig)
+	joinStackNetworks(ctx, cli, originalID, stackNetworks)
+
+	removeSessionState(*containerName)
 }
 
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+// sessionState is the on-disk record of an in-progress container swap. It is
+// written right after the original container is removed so that the swap
+// can be recovered with --resume or --abort even if this process is killed
+// before it gets the chance to restore the original container itself.
+type sessionState struct {
+	ContainerName     string                   `json:"containerName"`
+	DevContainerID    string                   `json:"devContainerId"`
+	OriginalContainer types.ContainerJSON      `json:"originalContainer"`
+	NetworkingConfig  network.NetworkingConfig `json:"networkingConfig"`
+	// StackNetworks holds the user-defined networks the container being
+	// swapped belonged to when it was part of a compose stack (-project),
+	// so the original can be re-joined to all of them on exit.
+	StackNetworks []string `json:"stackNetworks,omitempty"`
+	// SourcePath is kept so --resume can re-run postStart lifecycle hooks
+	// from the same devcontainer.json/.docker-dev.yml used at swap time.
+	SourcePath string `json:"sourcePath"`
+	// Project and Service record the -project/-stack and -service flags the
+	// swap was started with, if any, so --resume/--abort can be pointed at
+	// the same flags instead of requiring the resolved container name.
+	Project string `json:"project,omitempty"`
+	Service string `json:"service,omitempty"`
+}
+
+// sessionsDir returns ~/.docker-dev/sessions, creating it if necessary.
+func sessionsDir() (string, error) {
+	dir := filepath.Join(os.Getenv("HOME"), ".docker-dev", "sessions")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("creating sessions directory: %w", err)
+	}
+
+	return dir, nil
+}
+
+// sessionPath returns the state file path for the given original container name.
+func sessionPath(containerName string) (string, error) {
+	dir, err := sessionsDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, containerName+".json"), nil
+}
+
+// saveSessionState persists state so the swap can be recovered later.
+func saveSessionState(state *sessionState) error {
+	path, err := sessionPath(state.ContainerName)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling session state: %w", err)
+	}
+
+	return os.WriteFile(path, data, 0o600)
+}
+
+// loadSessionState reads back a previously saved session, if any.
+func loadSessionState(containerName string) (*sessionState, error) {
+	path, err := sessionPath(containerName)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no saved session for container %q", containerName)
+		}
+		return nil, fmt.Errorf("reading session state: %w", err)
+	}
+
+	var state sessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parsing session state: %w", err)
+	}
+
+	return &state, nil
+}
+
+// resolveStackSessionName finds the saved session started with -project (and
+// -service, if given) and returns the container name it was saved under, so
+// --resume/--abort can be driven by the same -project/-stack/-service flags
+// the swap itself used instead of requiring the resolved container name.
+func resolveStackSessionName(project, service string) (string, error) {
+	dir, err := sessionsDir()
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("reading sessions directory: %w", err)
+	}
+
+	var matches []sessionState
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+
+		var state sessionState
+		if err := json.Unmarshal(data, &state); err != nil {
+			continue
+		}
+
+		if state.Project != project {
+			continue
+		}
+		if service != "" && state.Service != service {
+			continue
+		}
+
+		matches = append(matches, state)
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no saved session for compose project %q", project)
+	case 1:
+		return matches[0].ContainerName, nil
+	default:
+		return "", fmt.Errorf("multiple saved sessions for compose project %q, pick one with -service", project)
+	}
+}
+
+// removeSessionState deletes the on-disk record once a swap is fully
+// resolved, i.e. the original container has been recreated.
+func removeSessionState(containerName string) {
+	path, err := sessionPath(containerName)
+	if err != nil {
+		return
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: could not remove session state for %q: %v", containerName, err)
+	}
+}
+
+// resumeSession re-attaches to the dev container for containerName's saved
+// session, starting it again if it has stopped, without removing the
+// original container a second time. When the session ends, the dev
+// container is removed and the original recreated, as after a normal swap.
+func resumeSession(ctx context.Context, cli *client.Client, containerName string) {
+	state, err := loadSessionState(containerName)
+	if err != nil {
+		log.Fatalf("Error resuming session: %v", err)
+	}
+
+	if state.DevContainerID == "" {
+		log.Fatalf("Saved session for %q has no dev container yet", containerName)
+	}
+
+	c, err := cli.ContainerInspect(ctx, state.DevContainerID)
+	if err != nil {
+		fatalUnlessRecoverable(fmt.Sprintf("inspecting dev container %s", state.DevContainerID), err)
+	}
+
+	if !c.State.Running {
+		if err := startContainerWithRecovery(ctx, cli, state.DevContainerID, c.Config.Image); err != nil {
+			fatalUnlessRecoverable(fmt.Sprintf("starting dev container %s", state.DevContainerID), err)
+		}
+	}
+
+	runLifecycleHooks(ctx, cli, state.DevContainerID, state.SourcePath)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+
+	attachToDevContainer(ctx, cli, sigCh, state.DevContainerID, c.Config.Tty)
+
+	println("Quit the container to restore the original one")
+
+	go func() {
+		waitForContainerStop(cli, ctx, state.DevContainerID)
+		println("Container has exited")
+		sigCh <- os.Interrupt
+	}()
+
+	<-sigCh
+	fmt.Println("Interrupt signal received, stopping container...")
+
+	resp := container.CreateResponse{ID: state.DevContainerID}
+	cleanupDevContainer(ctx, cli, &resp)
+	originalID := recreateOriginalContainer(ctx, cli, &state.OriginalContainer, &state.NetworkingConfig)
+	joinStackNetworks(ctx, cli, originalID, state.StackNetworks)
+	removeSessionState(containerName)
+}
+
+// abortSession restores the original container from a saved session even if
+// the dev container is gone, for when --resume is no longer possible.
+func abortSession(ctx context.Context, cli *client.Client, containerName string) {
+	state, err := loadSessionState(containerName)
+	if err != nil {
+		log.Fatalf("Error aborting session: %v", err)
+	}
+
+	originalID := recreateOriginalContainer(ctx, cli, &state.OriginalContainer, &state.NetworkingConfig)
+	joinStackNetworks(ctx, cli, originalID, state.StackNetworks)
+	removeSessionState(containerName)
+}
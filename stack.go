@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+// Compose sets these labels on every container it manages; docker-dev uses
+// them to discover a stack's containers without needing the compose CLI or
+// its project files.
+const (
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+)
+
+// stackContainer is one compose-managed container belonging to the project
+// docker-dev was pointed at via -project/-stack.
+type stackContainer struct {
+	ID       string
+	Name     string
+	Service  string
+	Networks []string
+}
+
+// listStackContainers returns every container belonging to the given
+// compose project, discovered via its com.docker.compose.project label.
+func listStackContainers(ctx context.Context, cli *client.Client, project string) ([]stackContainer, error) {
+	f := filters.NewArgs(filters.Arg("label", composeProjectLabel+"="+project))
+
+	containers, err := cli.ContainerList(ctx, container.ListOptions{All: true, Filters: f})
+	if err != nil {
+		return nil, fmt.Errorf("listing containers for compose project %s: %w", project, err)
+	}
+
+	result := make([]stackContainer, 0, len(containers))
+	for _, c := range containers {
+		name := ""
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+
+		var networks []string
+		if c.NetworkSettings != nil {
+			for netName := range c.NetworkSettings.Networks {
+				networks = append(networks, netName)
+			}
+		}
+
+		result = append(result, stackContainer{
+			ID:       c.ID,
+			Name:     name,
+			Service:  c.Labels[composeServiceLabel],
+			Networks: networks,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Service < result[j].Service })
+
+	return result, nil
+}
+
+// pickStackService resolves which container in the stack docker-dev should
+// swap in for: an exact com.docker.compose.service match when service is
+// set, or an interactive prompt over the project's containers otherwise.
+func pickStackService(containers []stackContainer, service string) (*stackContainer, error) {
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("no containers found for this compose project")
+	}
+
+	if service != "" {
+		for i := range containers {
+			if containers[i].Service == service {
+				return &containers[i], nil
+			}
+		}
+		return nil, fmt.Errorf("no container for service %q in this stack", service)
+	}
+
+	if len(containers) == 1 {
+		return &containers[0], nil
+	}
+
+	fmt.Println("Multiple services found in this stack, pick one:")
+	for i, c := range containers {
+		fmt.Printf("  [%d] %s (%s)\n", i+1, c.Service, c.Name)
+	}
+	fmt.Print("> ")
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("reading selection: %w", err)
+	}
+
+	var choice int
+	if _, err := fmt.Sscanf(strings.TrimSpace(line), "%d", &choice); err != nil || choice < 1 || choice > len(containers) {
+		return nil, fmt.Errorf("invalid selection %q", strings.TrimSpace(line))
+	}
+
+	return &containers[choice-1], nil
+}
+
+// joinStackNetworks attaches containerID to every network in networks, so a
+// swapped-in container stays reachable from its stack siblings by service
+// name, the way the container it replaced was. Networks containerID is
+// already attached to are skipped, rather than relying on the daemon's
+// "already exists in network" error text, which isn't guaranteed stable
+// across engine versions.
+func joinStackNetworks(ctx context.Context, cli *client.Client, containerID string, networks []string) {
+	c, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		fatalUnlessRecoverable(fmt.Sprintf("inspecting container %s before joining stack networks", containerID), err)
+	}
+
+	for _, netName := range networks {
+		if _, alreadyJoined := c.NetworkSettings.Networks[netName]; alreadyJoined {
+			continue
+		}
+
+		if err := cli.NetworkConnect(ctx, netName, containerID, &network.EndpointSettings{}); err != nil {
+			log.Printf("Warning: could not join network %s: %v", netName, err)
+		}
+	}
+}